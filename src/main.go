@@ -5,17 +5,15 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"html/template"
+	"io/fs"
 	"net"
 	"net/http"
-	"os"
 	"path"
 	"path/filepath"
 	"reflect"
-	"regexp"
 )
 
-//go:embed src/*gohtml tmpl/*gohtml tmpl/**/*gohtml
+//go:embed src/*gohtml src/*md src/*xml tmpl/*gohtml tmpl/**/*gohtml
 var UrlFS embed.FS
 
 //go:embed static/*
@@ -28,8 +26,16 @@ var (
 	Mux    *http.ServeMux
 	Config struct {
 		IsEmbed bool
+		// OverlayDir, when non-empty, is checked before embedded tmpl/
+		// static assets so a self-contained binary can still be
+		// customized without a rebuild.
+		OverlayDir string
 		Context struct {
 			Site map[string]any
+			// Page holds the per-page data merged in by RegisterPageData
+			// loaders, keyed by pageKey(srcPath). For a page served by a
+			// ContentRenderer it holds the rendered HTML under "Body".
+			Page map[string]any
 		}
 	}
 )
@@ -40,15 +46,32 @@ func init() {
 
 func init() {
 	isEmbed := flag.Bool("e", false, "True: embed, false: filesystem")
+	isLive := flag.Bool("live", false, "Force disk-backed templates, hot-reloaded via fsnotify, even if -e is set")
+	overlayDir := flag.String("overlay", "", "Directory of on-disk overrides for embedded tmpl/static assets")
 	flag.Parse()
-	Config.IsEmbed = *isEmbed
+	Config.IsEmbed = *isEmbed && !*isLive
+	Config.OverlayDir = *overlayDir
 	Config.Context.Site = map[string]any{"Title": "Demo"}
 }
 
-var reTmpl *regexp.Regexp
+// templateSource returns the TemplateSource to resolve templates from,
+// honoring Config.IsEmbed / Config.OverlayDir. Disk mode (IsEmbed false,
+// whether by default or forced via -live) is always hot-reloaded: see
+// watchTemplates.
+func templateSource() *TemplateSource {
+	if Config.IsEmbed {
+		return NewTemplateSource(overlayFS(UrlFS))
+	}
+	return DiskTemplateSource()
+}
 
-func init() {
-	reTmpl = regexp.MustCompile(`{{-? ?template \"(?P<Name>[^() ]*)\" ?.* ?-?}}`)
+// overlayFS wraps base with Config.OverlayDir when one is set, otherwise
+// returns base unchanged.
+func overlayFS(base fs.FS) fs.FS {
+	if Config.OverlayDir == "" {
+		return base
+	}
+	return NewOverlayFS(base, Config.OverlayDir)
 }
 
 func Dict(values ...any) (map[string]any, error) {
@@ -72,97 +95,40 @@ func Dict(values ...any) (map[string]any, error) {
 	return dict, nil
 }
 
-func CollectFilesFromFS(fs embed.FS, dirName string, isRecursive bool) (filepathList []string, err error) {
-	dirEntryList, err := fs.ReadDir(dirName)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, dirEntry := range dirEntryList {
-		if dirEntry.IsDir() {
-			if isRecursive {
-				fpList, _ := CollectFilesFromFS(fs, path.Join(dirName, dirEntry.Name()), isRecursive)
-				filepathList = append(filepathList, fpList...)
-			}
-			continue
-		}
-		filepathList = append(filepathList, path.Join(dirName, dirEntry.Name()))
-	}
-	return
-}
-
-func CollectFiles(dir string) (filepathList []string, err error) {
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-		filepathList = append(filepathList, path)
-		return nil
-	})
-	return
-}
-
-// getAllTmplName Get all template names, including nest.
-func getAllTmplName(filePath string, allTmpl []string, isEmbed bool) (filterTmpl []string, err error) {
-	var content []byte
-	if isEmbed {
-		content, err = UrlFS.ReadFile(filePath)
-	} else {
-		content, err = os.ReadFile(filePath)
-	}
-	if err != nil {
-		return
-	}
-	matchList := reTmpl.FindAllStringSubmatch(string(content), -1)
-
-	if len(matchList) == 0 {
-		return
-	}
-
-	curTmplSet := map[string]string{} // Know the names of all the templates used in the current file
-	for _, match := range matchList {
-		tmplName := match[1]
-		if _, exists := curTmplSet[tmplName]; exists {
-			continue
-		}
-		curTmplSet[tmplName] = tmplName
-	}
-
-	for _, tmplFilepath := range allTmpl { // Select the all used template from allTmpl.
-		_, exists := curTmplSet[filepath.Base(tmplFilepath)]
-		if exists {
-			filterTmpl = append(filterTmpl, tmplFilepath)
-			fList, _ := getAllTmplName(tmplFilepath, allTmpl, isEmbed) // The template may also have a template (sub-template) again, so look for it again.
-			if len(fList) > 0 {
-				filterTmpl = append(filterTmpl, fList...)
-			}
-		}
-	}
-	return
-}
-
 func initURL() {
 	Mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "image/svg+xml")
+		if Config.OverlayDir != "" {
+			if b, err := fs.ReadFile(overlayFS(staticFS), "static/img/favicon.svg"); err == nil {
+				_, _ = w.Write(b)
+				return
+			}
+		}
 		_, _ = w.Write(faviconBytes)
 	})
 
-	Mux.Handle("/static/", http.FileServer(http.FS(staticFS)))
+	Mux.Handle("/static/", http.FileServer(http.FS(overlayFS(staticFS))))
 
-	var (
-		tmplFileList []string
-		err          error
-	)
+	source := templateSource()
+	cache := newTemplateCache()
 	if Config.IsEmbed {
-		tmplFileList, err = CollectFilesFromFS(UrlFS, "tmpl", true)
+		if err := warmTemplateCache(cache, source); err != nil {
+			panic(err)
+		}
+		if Config.OverlayDir != "" {
+			// The embedded base can never change, but the overlay
+			// directory can; watch it so dropping in a replacement
+			// template invalidates the right cache entries.
+			watchOverlayDir(cache, Config.OverlayDir)
+		}
 	} else {
-		tmplFileList, err = CollectFiles("./tmpl")
-	}
-	if err != nil {
-		panic(err)
+		// Disk-backed templates can change underneath us; keep the cache
+		// honest instead of re-reading everything on every request.
+		watchTemplates(cache)
 	}
+
 	Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		curSrc := path.Join("./src", r.URL.Path)
+		curSrc := path.Join("src", r.URL.Path)
 		switch filepath.Ext(r.URL.Path) {
 		case "":
 			curSrc = path.Join(curSrc, "index.html")
@@ -172,31 +138,58 @@ func initURL() {
 			fallthrough
 		case ".gohtml":
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			filterTmpl, err := getAllTmplName(curSrc, tmplFileList, Config.IsEmbed)
+
+			entry, err := cache.resolve(source, curSrc)
 			if err != nil {
 				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(err.Error()))
 				return
 			}
-			filterTmpl = append(filterTmpl, curSrc) // Must include self
-			fmt.Printf("Templates used on this page:%+v\n", filterTmpl)
-			t := template.New(
-				filepath.Base(curSrc)).
-				Funcs(map[string]any{"dict": Dict})
-
-			if Config.IsEmbed {
-				t, err = t.ParseFS(UrlFS, filterTmpl...)
-			} else {
-				t, err = t.ParseFiles(filterTmpl...)
+
+			data := Config.Context
+			if loadPageData, ok := pageDataLoaders[curSrc]; ok {
+				pageData, err := loadPageData(r)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					_, _ = w.Write([]byte(err.Error()))
+					return
+				}
+				data.Page = map[string]any{pageKey(curSrc): pageData}
+			}
+
+			if err = entry.tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(err.Error()))
+			}
+		default:
+			renderer, ok := contentRenderers[filepath.Ext(r.URL.Path)]
+			if !ok {
+				http.FileServer(http.Dir(".")).ServeHTTP(w, r)
+				return
 			}
 
+			// Root content files under src/, same as gohtml pages.
+			body, err := renderer.Render(source, path.Join("src", r.URL.Path))
 			if err != nil {
 				w.WriteHeader(http.StatusBadRequest)
 				_, _ = w.Write([]byte(err.Error()))
 				return
 			}
-			_ = t.Execute(w, Config.Context)
-		default:
-			http.FileServer(http.Dir("."))
+
+			entry, err := cache.resolve(source, contentLayoutSrc)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(err.Error()))
+				return
+			}
+
+			data := Config.Context
+			data.Page = map[string]any{"Body": body}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err = entry.tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(err.Error()))
+			}
 		}
 	})
 }
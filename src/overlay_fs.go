@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// OverlayFS composes a base filesystem (typically an embedded one) with an
+// on-disk overlay directory: a file present in the overlay wins, and
+// anything missing there falls through to base. This lets a self-contained
+// binary still be customized by dropping a replacement file next to it,
+// without a rebuild.
+type OverlayFS struct {
+	base    fs.FS
+	overlay fs.FS
+}
+
+// NewOverlayFS returns base unchanged as an OverlayFS with no overlay when
+// overlayDir is empty.
+func NewOverlayFS(base fs.FS, overlayDir string) *OverlayFS {
+	var overlay fs.FS
+	if overlayDir != "" {
+		overlay = os.DirFS(overlayDir)
+	}
+	return &OverlayFS{base: base, overlay: overlay}
+}
+
+func (o *OverlayFS) Open(name string) (fs.File, error) {
+	if o.overlay != nil {
+		if f, err := o.overlay.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return o.base.Open(name)
+}
+
+func (o *OverlayFS) ReadFile(name string) ([]byte, error) {
+	if o.overlay != nil {
+		if b, err := fs.ReadFile(o.overlay, name); err == nil {
+			return b, nil
+		}
+	}
+	return fs.ReadFile(o.base, name)
+}
+
+// ReadDir merges the overlay and base directory listings, with overlay
+// entries winning on name collisions.
+func (o *OverlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	baseEntries, baseErr := fs.ReadDir(o.base, name)
+	if o.overlay == nil {
+		return baseEntries, baseErr
+	}
+
+	overlayEntries, overlayErr := fs.ReadDir(o.overlay, name)
+	if overlayErr != nil {
+		return baseEntries, baseErr
+	}
+	if baseErr != nil {
+		return overlayEntries, nil
+	}
+
+	byName := make(map[string]fs.DirEntry, len(baseEntries)+len(overlayEntries))
+	for _, e := range baseEntries {
+		byName[e.Name()] = e
+	}
+	for _, e := range overlayEntries {
+		byName[e.Name()] = e
+	}
+
+	merged := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		merged = append(merged, e)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
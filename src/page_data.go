@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// PageDataFunc loads page-specific data for a single template at request
+// time. It receives the incoming request so loaders can read query params,
+// headers, etc.
+type PageDataFunc func(*http.Request) (any, error)
+
+var pageDataLoaders = map[string]PageDataFunc{}
+
+// RegisterPageData pairs a page template (e.g. "src/about.gohtml") with a
+// loader. When that page is served, fn's return value is merged into
+// Config.Context.Page under the page's key (pageKey(srcPath)) before the
+// "layout" template is executed, so the page can reach it without any
+// change to initURL.
+func RegisterPageData(srcPath string, fn PageDataFunc) {
+	pageDataLoaders[srcPath] = fn
+}
+
+// pageKey derives the Config.Context.Page key for a template source path,
+// e.g. "src/about.gohtml" -> "about".
+func pageKey(srcPath string) string {
+	base := filepath.Base(srcPath)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
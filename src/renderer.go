@@ -0,0 +1,23 @@
+package main
+
+import "html/template"
+
+// contentLayoutSrc is the shared layout used to wrap any ContentRenderer's
+// output (as opposed to a gohtml page, which declares its own "layout").
+// It lives under tmpl/ so it is parsed alongside every other template.
+const contentLayoutSrc = "tmpl/content_layout.gohtml"
+
+// ContentRenderer renders a non-gohtml source file (Markdown, a codewalk
+// XML file, ...) into the HTML body that gets embedded in contentLayoutSrc.
+type ContentRenderer interface {
+	Render(source *TemplateSource, rawPath string) (template.HTML, error)
+}
+
+var contentRenderers = map[string]ContentRenderer{}
+
+// RegisterRenderer associates a ContentRenderer with a URL file extension
+// (e.g. ".md"), so new content types can be added without touching the
+// root handler's dispatch logic.
+func RegisterRenderer(ext string, renderer ContentRenderer) {
+	contentRenderers[ext] = renderer
+}
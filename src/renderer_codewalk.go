@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Codewalk mirrors the XML format used by golang.org/x/tools' codewalk
+// tool: a title plus an ordered list of steps, each pointing at a source
+// file (optionally a line range) with accompanying commentary.
+type Codewalk struct {
+	XMLName xml.Name       `xml:"codewalk"`
+	Title   string         `xml:"title,attr"`
+	Steps   []CodewalkStep `xml:"step"`
+}
+
+// CodewalkStep is one <step> of a Codewalk. Src is "path/to/file.go",
+// "path/to/file.go:line", or "path/to/file.go:startLine,endLine" to narrow
+// the snippet shown.
+type CodewalkStep struct {
+	Src     string `xml:"src,attr"`
+	Title   string `xml:"title,attr"`
+	Comment string `xml:",chardata"`
+}
+
+// CodewalkRenderer renders .xml codewalk files, pulling each step's
+// referenced snippet from the same TemplateSource so it always matches
+// what is actually on disk or embedded in the binary.
+type CodewalkRenderer struct{}
+
+func (CodewalkRenderer) Render(source *TemplateSource, rawPath string) (template.HTML, error) {
+	raw, err := source.ReadFile(rawPath)
+	if err != nil {
+		return "", err
+	}
+	var walk Codewalk
+	if err = xml.Unmarshal(raw, &walk); err != nil {
+		return "", err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<h1>%s</h1>\n", template.HTMLEscapeString(walk.Title))
+	for _, step := range walk.Steps {
+		snippet, err := codewalkSnippet(source, step.Src)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "<section class=\"codewalk-step\">\n"+
+			"  <h2>%s</h2>\n"+
+			"  <div class=\"codewalk-comment\">%s</div>\n"+
+			"  <pre class=\"codewalk-src\"><code>%s</code></pre>\n"+
+			"</section>\n",
+			template.HTMLEscapeString(step.Title),
+			template.HTMLEscapeString(step.Comment),
+			template.HTMLEscapeString(snippet))
+	}
+	return template.HTML(body.String()), nil
+}
+
+// codewalkSnippet resolves a step's "path[:line]" or "path[:startLine,endLine]"
+// reference and returns the requested lines, or the whole file when no range
+// is given.
+func codewalkSnippet(source *TemplateSource, src string) (string, error) {
+	filePath, startLine, endLine := src, 0, 0
+	if idx := strings.LastIndex(src, ":"); idx != -1 {
+		var start, end int
+		if _, err := fmt.Sscanf(src[idx+1:], "%d,%d", &start, &end); err == nil {
+			filePath, startLine, endLine = src[:idx], start, end
+		} else if _, err = fmt.Sscanf(src[idx+1:], "%d", &start); err == nil {
+			filePath, startLine, endLine = src[:idx], start, start
+		}
+	}
+
+	content, err := source.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	if startLine == 0 {
+		return string(content), nil
+	}
+
+	lines := strings.Split(string(content), "\n")
+	if startLine < 1 {
+		startLine = 1
+	}
+	if startLine > len(lines)+1 {
+		startLine = len(lines) + 1
+	}
+	if endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine-1 > endLine {
+		return "", nil
+	}
+	return strings.Join(lines[startLine-1:endLine], "\n"), nil
+}
+
+func init() {
+	RegisterRenderer(".xml", CodewalkRenderer{})
+}
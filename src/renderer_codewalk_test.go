@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCodewalkSnippet(t *testing.T) {
+	source := NewTemplateSource(fstest.MapFS{
+		"file.go": &fstest.MapFile{Data: []byte("one\ntwo\nthree\nfour\nfive\n")},
+	})
+
+	cases := map[string]string{
+		"file.go":       "one\ntwo\nthree\nfour\nfive\n",
+		"file.go:2":     "two",
+		"file.go:2,3":   "two\nthree",
+		"file.go:8,100": "", // start past EOF must not panic, just come back empty
+	}
+	for src, want := range cases {
+		got, err := codewalkSnippet(source, src)
+		if err != nil {
+			t.Fatalf("codewalkSnippet(%q): %v", src, err)
+		}
+		if got != want {
+			t.Errorf("codewalkSnippet(%q) = %q, want %q", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/yuin/goldmark"
+)
+
+// MarkdownRenderer renders .md files with goldmark.
+type MarkdownRenderer struct{}
+
+func (MarkdownRenderer) Render(source *TemplateSource, rawPath string) (template.HTML, error) {
+	src, err := source.ReadFile(rawPath)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = goldmark.Convert(src, &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func init() {
+	RegisterRenderer(".md", MarkdownRenderer{})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"html/template"
+	"path/filepath"
+	"sync"
+)
+
+// cachedTemplate is a resolved page template plus the set of source files
+// (tmpl/* deps and the page itself) it was built from, so a single changed
+// file can invalidate just the entries that depend on it.
+type cachedTemplate struct {
+	tmpl   *template.Template
+	depSet map[string]struct{}
+}
+
+// templateCache memoizes the parsed template per page (keyed the same way
+// as pageDataLoaders, by curSrc). It is safe for concurrent use.
+type templateCache struct {
+	mu      sync.RWMutex
+	entries map[string]*cachedTemplate
+}
+
+func newTemplateCache() *templateCache {
+	return &templateCache{entries: map[string]*cachedTemplate{}}
+}
+
+// resolve returns the cached template for curSrc, building and storing it
+// on a cache miss.
+func (c *templateCache) resolve(source *TemplateSource, curSrc string) (*cachedTemplate, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[curSrc]
+	c.mu.RUnlock()
+	if ok {
+		return entry, nil
+	}
+	return c.build(source, curSrc)
+}
+
+// build parses curSrc together with every file under tmpl/ and stores the
+// result, overwriting any existing entry.
+func (c *templateCache) build(source *TemplateSource, curSrc string) (*cachedTemplate, error) {
+	tmplFileList, err := CollectFiles(source, "tmpl", true)
+	if err != nil {
+		return nil, err
+	}
+	parseList := append(tmplFileList, curSrc)
+
+	tmpl, err := template.New(filepath.Base(curSrc)).
+		Funcs(map[string]any{"dict": Dict}).
+		ParseFS(source, parseList...)
+	if err != nil {
+		return nil, err
+	}
+
+	depSet := make(map[string]struct{}, len(parseList))
+	for _, p := range parseList {
+		depSet[p] = struct{}{}
+	}
+
+	entry := &cachedTemplate{tmpl: tmpl, depSet: depSet}
+	c.mu.Lock()
+	c.entries[curSrc] = entry
+	c.mu.Unlock()
+	return entry, nil
+}
+
+// invalidate drops every cache entry whose dependency set contains
+// changedFile.
+func (c *templateCache) invalidate(changedFile string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if _, ok := entry.depSet[changedFile]; ok {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// warmTemplateCache builds a cache entry for every *.gohtml page under src/
+// up front. Used in embed mode, where the tree can never change underneath
+// us, so there is no point resolving lazily.
+func warmTemplateCache(cache *templateCache, source *TemplateSource) error {
+	srcFiles, err := CollectFiles(source, "src", true)
+	if err != nil {
+		return err
+	}
+	for _, f := range srcFiles {
+		if filepath.Ext(f) != ".gohtml" {
+			continue
+		}
+		if _, err = cache.build(source, f); err != nil {
+			return err
+		}
+	}
+	if len(contentRenderers) > 0 {
+		if _, err = cache.build(source, contentLayoutSrc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
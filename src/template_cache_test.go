@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// buildBenchFS returns an in-memory filesystem (to be wrapped with
+// NewTemplateSource) for a page that pulls in n sub-templates under tmpl/,
+// so resolve()'s dependency-set build cost scales with n.
+func buildBenchFS(n int) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	var page strings.Builder
+	page.WriteString(`{{define "layout"}}<html>`)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("partial%d", i)
+		fmt.Fprintf(&page, `{{template "%s" .}}`, name)
+		fsys[fmt.Sprintf("tmpl/%s.gohtml", name)] = &fstest.MapFile{
+			Data: []byte(fmt.Sprintf(`{{define "%s"}}chunk-%d{{end}}`, name, i)),
+		}
+	}
+	page.WriteString(`</html>{{end}}`)
+	fsys["src/page.gohtml"] = &fstest.MapFile{Data: []byte(page.String())}
+	return fsys
+}
+
+func TestTemplateCacheResolveInvalidate(t *testing.T) {
+	source := NewTemplateSource(buildBenchFS(3))
+	cache := newTemplateCache()
+
+	first, err := cache.resolve(source, "src/page.gohtml")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	second, err := cache.resolve(source, "src/page.gohtml")
+	if err != nil {
+		t.Fatalf("resolve (cache hit): %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected a cache hit to return the same *cachedTemplate")
+	}
+
+	cache.invalidate("tmpl/partial1.gohtml")
+	third, err := cache.resolve(source, "src/page.gohtml")
+	if err != nil {
+		t.Fatalf("resolve (after invalidate): %v", err)
+	}
+	if third == second {
+		t.Fatalf("expected invalidate to force a rebuild")
+	}
+}
+
+// BenchmarkTemplateCacheResolve compares resolving a page with a dozen
+// tmpl/ dependencies cold (reparsed every call, as initURL did pre-cache)
+// against warm (served from templateCache).
+func BenchmarkTemplateCacheResolve(b *testing.B) {
+	source := NewTemplateSource(buildBenchFS(12))
+
+	b.Run("cold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cache := newTemplateCache()
+			if _, err := cache.resolve(source, "src/page.gohtml"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("warm", func(b *testing.B) {
+		cache := newTemplateCache()
+		if _, err := cache.resolve(source, "src/page.gohtml"); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := cache.resolve(source, "src/page.gohtml"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
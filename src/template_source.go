@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+)
+
+// TemplateSource unifies the embedded (UrlFS/staticFS) and on-disk (os.DirFS)
+// filesystems used to resolve template files, so callers no longer need to
+// branch on Config.IsEmbed. It implements fs.FS directly, so it can be passed
+// straight to html/template's ParseFS.
+type TemplateSource struct {
+	fsys fs.FS
+}
+
+// NewTemplateSource wraps fsys so it can be used uniformly by the template
+// resolution code below.
+func NewTemplateSource(fsys fs.FS) *TemplateSource {
+	return &TemplateSource{fsys: fsys}
+}
+
+// DiskTemplateSource returns a TemplateSource backed by the current working
+// directory, rooted the same way the embedded source is (i.e. paths like
+// "tmpl/foo.gohtml" rather than "./tmpl/foo.gohtml").
+func DiskTemplateSource() *TemplateSource {
+	return DiskTemplateSourceAt(".")
+}
+
+// DiskTemplateSourceAt returns a TemplateSource backed by dir, rooted the
+// same way DiskTemplateSource is.
+func DiskTemplateSourceAt(dir string) *TemplateSource {
+	return NewTemplateSource(os.DirFS(dir))
+}
+
+func (t *TemplateSource) Open(name string) (fs.File, error) {
+	return t.fsys.Open(name)
+}
+
+func (t *TemplateSource) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(t.fsys, name)
+}
+
+func (t *TemplateSource) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(t.fsys, name)
+}
+
+// Walk walks root in the wrapped filesystem, same semantics as fs.WalkDir.
+func (t *TemplateSource) Walk(root string, fn fs.WalkDirFunc) error {
+	return fs.WalkDir(t.fsys, root, fn)
+}
+
+// CollectFiles returns every file path under dirName, optionally recursing
+// into subdirectories. It replaces the old pair of CollectFilesFromFS /
+// CollectFiles functions that duplicated this logic for embed.FS and disk.
+func CollectFiles(source *TemplateSource, dirName string, isRecursive bool) (filepathList []string, err error) {
+	dirEntryList, err := source.ReadDir(dirName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dirEntry := range dirEntryList {
+		if dirEntry.IsDir() {
+			if isRecursive {
+				fpList, _ := CollectFiles(source, path.Join(dirName, dirEntry.Name()), isRecursive)
+				filepathList = append(filepathList, fpList...)
+			}
+			continue
+		}
+		filepathList = append(filepathList, path.Join(dirName, dirEntry.Name()))
+	}
+	return
+}
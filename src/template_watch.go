@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"path"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchTemplates attaches an fsnotify watcher over ./tmpl and ./src and
+// invalidates the cache entries affected by each change. Used whenever
+// templates can change on disk after startup: disk-backed mode (Config.
+// IsEmbed false), and embed mode with an overlay directory, via
+// watchOverlayDir below.
+func watchTemplates(cache *templateCache) {
+	watchDir(cache, ".")
+}
+
+// watchOverlayDir watches an overlay directory's tmpl/ and src/ subtrees so
+// a file dropped in after startup invalidates the same cache entries a
+// rebuild would have, even though the embedded base never changes.
+func watchOverlayDir(cache *templateCache, overlayDir string) {
+	watchDir(cache, overlayDir)
+}
+
+// watchDir watches root's tmpl/ and src/ subtrees, translating fsnotify
+// events back to the root-relative, forward-slash paths templateCache keys
+// its dependency sets by (e.g. "tmpl/header.gohtml").
+func watchDir(cache *templateCache, root string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("templates: fsnotify disabled for %s, cached templates will not be reloaded until restart: %v", root, err)
+		return
+	}
+
+	source := DiskTemplateSourceAt(root)
+	for _, dir := range []string{"tmpl", "src"} {
+		if err = addWatchRecursive(watcher, source, filepath.Join(root, dir), dir); err != nil {
+			log.Printf("templates: could not watch %s under %s: %v", dir, root, err)
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					rel, relErr := filepath.Rel(root, event.Name)
+					if relErr != nil {
+						continue
+					}
+					cache.invalidate(filepath.ToSlash(rel))
+				}
+			case watchErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("templates: watcher error: %v", watchErr)
+			}
+		}
+	}()
+}
+
+// addWatchRecursive registers realDir (and every subdirectory under it,
+// resolved through source using the root-relative relDir) with watcher,
+// since fsnotify does not watch directories recursively on its own.
+func addWatchRecursive(watcher *fsnotify.Watcher, source *TemplateSource, realDir, relDir string) error {
+	if err := watcher.Add(realDir); err != nil {
+		return err
+	}
+	entries, err := source.ReadDir(relDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			next := path.Join(relDir, entry.Name())
+			if err = addWatchRecursive(watcher, source, filepath.Join(realDir, entry.Name()), next); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}